@@ -0,0 +1,169 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package amqp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func roundTrip(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	bytes, err := Marshal(v, nil)
+	if err != nil {
+		t.Fatalf("Marshal(%#v): %v", v, err)
+	}
+	var out interface{}
+	if _, err := Unmarshal(bytes, &out); err != nil {
+		t.Fatalf("Unmarshal(%#v): %v", v, err)
+	}
+	return out
+}
+
+func TestArrayRoundTrip(t *testing.T) {
+	for _, want := range []Array{
+		{ElementType: Int, Values: []int32{1, 2, 3}},
+		{ElementType: String_, Values: []string{"a", "b", "c"}},
+		{ElementType: SymbolType, Values: []Symbol{"x", "y"}},
+		{ElementType: Int, Values: []int32{}},
+	} {
+		got := roundTrip(t, want)
+		array, ok := got.(Array)
+		if !ok {
+			t.Fatalf("roundTrip(%#v) = %#v, want Array", want, got)
+		}
+		if array.ElementType != want.ElementType {
+			t.Errorf("roundTrip(%#v).ElementType = %v, want %v", want, array.ElementType, want.ElementType)
+		}
+		if !reflect.DeepEqual(array.Values, want.Values) {
+			t.Errorf("roundTrip(%#v).Values = %#v, want %#v", want, array.Values, want.Values)
+		}
+	}
+}
+
+// Plain Go slices of a homogeneous primitive type marshal as an AMQP array
+// and round-trip back to an Array, per Marshal's documented slice-to-array
+// conversion.
+func TestSliceRoundTripsAsArray(t *testing.T) {
+	got := roundTrip(t, []int32{1, 2, 3})
+	array, ok := got.(Array)
+	if !ok {
+		t.Fatalf("roundTrip([]int32{...}) = %#v, want Array", got)
+	}
+	if array.ElementType != Int {
+		t.Errorf("roundTrip([]int32{...}).ElementType = %v, want Int", array.ElementType)
+	}
+	if !reflect.DeepEqual(array.Values, []int32{1, 2, 3}) {
+		t.Errorf("roundTrip([]int32{...}).Values = %#v, want [1 2 3]", array.Values)
+	}
+}
+
+// A named type whose underlying kind maps to an AMQP primitive (e.g. type
+// Priority int32) is array-eligible per amqpElementType, and must round-trip
+// through Marshal/Unmarshal like the builtin type it's defined from.
+type arrayTestPriority int32
+
+func TestNamedScalarTypeSliceRoundTrip(t *testing.T) {
+	got := roundTrip(t, []arrayTestPriority{1, 2, 3})
+	array, ok := got.(Array)
+	if !ok {
+		t.Fatalf("roundTrip([]arrayTestPriority{...}) = %#v, want Array", got)
+	}
+	if array.ElementType != Int {
+		t.Errorf("roundTrip([]arrayTestPriority{...}).ElementType = %v, want Int", array.ElementType)
+	}
+	if !reflect.DeepEqual(array.Values, []int32{1, 2, 3}) {
+		t.Errorf("roundTrip([]arrayTestPriority{...}).Values = %#v, want [1 2 3]", array.Values)
+	}
+}
+
+// The same holds for an explicit Array wrapper around a named scalar type.
+func TestArrayOfNamedScalarTypeRoundTrip(t *testing.T) {
+	want := Array{ElementType: Int, Values: []arrayTestPriority{4, 5}}
+	got := roundTrip(t, want)
+	array, ok := got.(Array)
+	if !ok {
+		t.Fatalf("roundTrip(%#v) = %#v, want Array", want, got)
+	}
+	if !reflect.DeepEqual(array.Values, []int32{4, 5}) {
+		t.Errorf("roundTrip(%#v).Values = %#v, want [4 5]", want, array.Values)
+	}
+}
+
+// []interface{} always encodes as a list, never an array, since its elements
+// may have mixed types.
+func TestMixedSliceRoundTripsAsList(t *testing.T) {
+	got := roundTrip(t, []interface{}{int32(1), "two", Symbol("three")})
+	list, ok := got.(List)
+	if !ok {
+		t.Fatalf("roundTrip([]interface{}{...}) = %#v, want List", got)
+	}
+	want := List{int32(1), "two", Symbol("three")}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("roundTrip([]interface{}{...}) = %#v, want %#v", list, want)
+	}
+}
+
+// RegisterDescribed makes an Array of a registered described type round-trip
+// through Marshal/Unmarshal without an explicit Described{} wrapper; here the
+// element type is a described struct rather than an AMQP primitive, so the
+// Array is Marshal'd with its ElementType set to Described_ and each element
+// dereferenced to the registered struct.
+type arrayTestDescribed struct {
+	Described struct{} `amqp:",described=0x7777:array-test"`
+	Name      string
+}
+
+func TestArrayOfDescribedTypeRoundTrip(t *testing.T) {
+	MustRegisterDescribed(Symbol("0x7777:array-test"), arrayTestDescribed{})
+
+	want := Array{
+		ElementType: Described_,
+		Values: []arrayTestDescribed{
+			{Name: "first"},
+			{Name: "second"},
+		},
+	}
+	bytes, err := Marshal(want, nil)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var out interface{}
+	if _, err := Unmarshal(bytes, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	array, ok := out.(Array)
+	if !ok {
+		t.Fatalf("Unmarshal = %#v, want Array", out)
+	}
+	values, ok := array.Values.([]interface{})
+	if !ok || len(values) != 2 {
+		t.Fatalf("array.Values = %#v, want a 2-element []interface{}", array.Values)
+	}
+	for i, wantName := range []string{"first", "second"} {
+		got, ok := values[i].(*arrayTestDescribed)
+		if !ok {
+			t.Fatalf("array.Values[%d] = %#v, want *arrayTestDescribed", i, values[i])
+		}
+		if got.Name != wantName {
+			t.Errorf("array.Values[%d].Name = %q, want %q", i, got.Name, wantName)
+		}
+	}
+}
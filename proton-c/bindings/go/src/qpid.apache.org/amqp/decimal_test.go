@@ -0,0 +1,62 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package amqp
+
+import "testing"
+
+func TestDecimal32RoundTrip(t *testing.T) {
+	for _, want := range []Decimal32{0, 1, 0x32800001, 0xffffffff} {
+		got := roundTrip(t, want)
+		if got != want {
+			t.Errorf("roundTrip(%#v) = %#v, want %#v", want, got, want)
+		}
+	}
+}
+
+func TestDecimal64RoundTrip(t *testing.T) {
+	for _, want := range []Decimal64{0, 1, 0x2080000000000001, 0xffffffffffffffff} {
+		got := roundTrip(t, want)
+		if got != want {
+			t.Errorf("roundTrip(%#v) = %#v, want %#v", want, got, want)
+		}
+	}
+}
+
+func TestDecimal128RoundTrip(t *testing.T) {
+	want := Decimal128{0x2e, 0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	got := roundTrip(t, want)
+	d, ok := got.(Decimal128)
+	if !ok || d != want {
+		t.Errorf("roundTrip(%#v) = %#v, want %#v", want, got, want)
+	}
+}
+
+// Decimal values nested in a List round-trip as well as bare values.
+func TestDecimalInContainerRoundTrip(t *testing.T) {
+	want := List{Decimal32(1), Decimal64(2), Decimal128{15: 3}}
+	got := roundTrip(t, want)
+	list, ok := got.(List)
+	if !ok || len(list) != 3 {
+		t.Fatalf("roundTrip(%#v) = %#v, want a 3-element List", want, got)
+	}
+	if list[0] != want[0] || list[1] != want[1] || list[2] != want[2] {
+		t.Errorf("roundTrip(%#v) = %#v, want %#v", want, list, want)
+	}
+}
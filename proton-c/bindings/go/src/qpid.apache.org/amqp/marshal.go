@@ -106,10 +106,33 @@ Go types are encoded as follows
  +-------------------------------------+--------------------------------------------+
  |UUID                                 |uuid                                        |
  +-------------------------------------+--------------------------------------------+
+ |Array                                |array                                       |
+ +-------------------------------------+--------------------------------------------+
+ |Decimal32, Decimal64, Decimal128     |decimal32, decimal64, decimal128            |
+ +-------------------------------------+--------------------------------------------+
+ |struct                               |map, or described type (see below)          |
+ +-------------------------------------+--------------------------------------------+
+
+A []T or Array whose elements all share a single Go type that maps to an AMQP
+primitive (e.g. []int32, []string, []Symbol) is encoded as an AMQP array.
+A []T whose T has kind int or uint (e.g. []int, []uint) is not: it is
+encoded as an AMQP list.
+[]interface{} and List are always encoded as an AMQP list, since their values
+may have mixed types.
 
-The following Go types cannot be marshaled: uintptr, function, channel, array (use slice), struct, complex64/128.
+A struct is encoded as a map, one key/value pair per exported field. Fields
+may carry an `amqp:"name,omitempty,symbol"` tag to override the map key,
+omit the field when it holds its zero value, or encode the key as a Symbol;
+`amqp:"-"` skips the field. A field tagged `amqp:",described=<descriptor>"`
+instead makes the whole struct marshal as a Described value with that
+descriptor and the other fields as the list body, as used by AMQP composite
+types.
 
-AMQP types not yet supported: decimal32/64/128, array.
+A Go type registered with RegisterDescribed marshals as a Described value
+with the registered descriptor automatically, without requiring a
+`described=` tag or an explicit Described{...} wrapper at the call site.
+
+The following Go types cannot be marshaled: uintptr, function, channel, array (use slice), complex64/128.
 */
 func Marshal(v interface{}, buffer []byte) (outbuf []byte, err error) {
 	defer recoverMarshal(&err)
@@ -228,12 +251,47 @@ func marshal(v interface{}, data *C.pn_data_t) {
 		C.pn_data_put_uuid(data, *(*C.pn_uuid_t)(unsafe.Pointer(&v[0])))
 	case Char:
 		C.pn_data_put_char(data, (C.pn_char_t)(v))
+	case Decimal32:
+		C.pn_data_put_decimal32(data, C.pn_decimal32_t(v))
+	case Decimal64:
+		C.pn_data_put_decimal64(data, C.pn_decimal64_t(v))
+	case Decimal128:
+		C.pn_data_put_decimal128(data, *(*C.pn_decimal128_t)(unsafe.Pointer(&v[0])))
+	case Array:
+		putArray(data, v.ElementType, reflect.ValueOf(v.Values))
 	default:
-		switch reflect.TypeOf(v).Kind() {
+		// Dereference pointers (e.g. a *T returned by unmarshaling a
+		// registered described type) so a nil pointer always marshals as
+		// null and descriptorFor/the Kind switch below see T, not *T;
+		// RegisterDescribed stores its Go types dereferenced the same way.
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				C.pn_data_put_null(data)
+				rv = reflect.Value{}
+				break
+			}
+			rv = rv.Elem()
+		}
+		if !rv.IsValid() {
+			break
+		}
+		t := rv.Type()
+		if descriptor, ok := descriptorFor(t); ok {
+			C.pn_data_put_described(data)
+			C.pn_data_enter(data)
+			marshal(descriptor, data)
+			marshalDescribedBody(data, rv.Interface())
+			C.pn_data_exit(data)
+			break
+		}
+		switch t.Kind() {
 		case reflect.Map:
-			putMap(data, v)
+			putMap(data, rv.Interface())
 		case reflect.Slice:
-			putList(data, v)
+			putSlice(data, rv.Interface())
+		case reflect.Struct:
+			marshalStruct(data, rv.Interface())
 		default:
 			panic(newMarshalError(v, "no conversion"))
 		}
@@ -270,18 +328,146 @@ func putList(data *C.pn_data_t, v interface{}) {
 	C.pn_data_exit(data)
 }
 
+// putSlice encodes a Go slice as an AMQP array if every element has the same
+// concrete type that maps to an AMQP primitive (per amqpElementType, which
+// has no case for the int and uint kinds), otherwise as an AMQP list.
+// []interface{} (and the List type) always encode as a list, preserving the
+// pre-array encoding for mixed-type sequences.
+func putSlice(data *C.pn_data_t, v interface{}) {
+	elemType := reflect.TypeOf(v).Elem()
+	if elemType.Kind() != reflect.Interface {
+		if amqpType, ok := amqpElementType(elemType); ok {
+			putArray(data, amqpType, reflect.ValueOf(v))
+			return
+		}
+	}
+	putList(data, v)
+}
+
+// putArray encodes values (a slice whose elements are all of the same Go
+// type) as an AMQP array with the given element type, writing the element
+// constructor once as pn_data_put_array requires.
+func putArray(data *C.pn_data_t, elementType AMQPType, values reflect.Value) {
+	C.pn_data_put_array(data, C.bool(false), C.pn_type_t(elementType))
+	C.pn_data_enter(data)
+	for i := 0; i < values.Len(); i++ {
+		marshal(canonicalElement(values.Index(i)), data)
+	}
+	C.pn_data_exit(data)
+}
+
+// canonicalElement converts v to the exact builtin type marshal's type
+// switch matches for its Kind (e.g. a `type Priority int32` value to a plain
+// int32), since a Go type switch matches dynamic type identity, not
+// underlying kind, and amqpElementType accepts any named type whose
+// underlying kind maps to an AMQP primitive. Types marshal already matches
+// by name (Symbol, Char, UUID, Binary, time.Time, the Decimal types) are
+// left unchanged.
+func canonicalElement(v reflect.Value) interface{} {
+	switch v.Type() {
+	case reflect.TypeOf(Symbol("")), reflect.TypeOf(Char(0)), reflect.TypeOf(UUID{}),
+		reflect.TypeOf(Binary("")), reflect.TypeOf(time.Time{}),
+		reflect.TypeOf(Decimal32(0)), reflect.TypeOf(Decimal64(0)), reflect.TypeOf(Decimal128{}):
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int8:
+		return int8(v.Int())
+	case reflect.Int16:
+		return int16(v.Int())
+	case reflect.Int32:
+		return int32(v.Int())
+	case reflect.Int64:
+		return int64(v.Int())
+	case reflect.Uint8:
+		return uint8(v.Uint())
+	case reflect.Uint16:
+		return uint16(v.Uint())
+	case reflect.Uint32:
+		return uint32(v.Uint())
+	case reflect.Uint64:
+		return uint64(v.Uint())
+	case reflect.Float32:
+		return float32(v.Float())
+	case reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	default:
+		return v.Interface()
+	}
+}
+
+// amqpElementType returns the AMQPType that a homogeneous slice of Go type t
+// would encode as, and whether t is eligible to be encoded as an Array
+// element type at all.
+func amqpElementType(t reflect.Type) (AMQPType, bool) {
+	switch t {
+	case reflect.TypeOf(Symbol("")):
+		return SymbolType, true
+	case reflect.TypeOf(Char(0)):
+		return Char_, true
+	case reflect.TypeOf(UUID{}):
+		return UuidType, true
+	case reflect.TypeOf(Binary("")):
+		return Binary_, true
+	case reflect.TypeOf(time.Time{}):
+		return Timestamp, true
+	case reflect.TypeOf(Decimal32(0)):
+		return Decimal32Type, true
+	case reflect.TypeOf(Decimal64(0)):
+		return Decimal64Type, true
+	case reflect.TypeOf(Decimal128{}):
+		return Decimal128Type, true
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return Bool, true
+	case reflect.Int8:
+		return Byte, true
+	case reflect.Int16:
+		return Short, true
+	case reflect.Int32:
+		return Int, true
+	case reflect.Int64:
+		return Long, true
+	case reflect.Uint8:
+		return Ubyte, true
+	case reflect.Uint16:
+		return Ushort, true
+	case reflect.Uint32:
+		return Uint, true
+	case reflect.Uint64:
+		return Ulong, true
+	case reflect.Float32:
+		return Float, true
+	case reflect.Float64:
+		return Double, true
+	case reflect.String:
+		return String_, true
+	default:
+		return Null, false
+	}
+}
+
 // Encoder encodes AMQP values to an io.Writer
 type Encoder struct {
 	writer io.Writer
 	buffer []byte
+	stream *streamState
 }
 
 // New encoder returns a new encoder that writes to w.
 func NewEncoder(w io.Writer) *Encoder {
-	return &Encoder{w, make([]byte, minEncode)}
+	return &Encoder{writer: w, buffer: make([]byte, minEncode)}
 }
 
 func (e *Encoder) Encode(v interface{}) (err error) {
+	if e.stream != nil {
+		return e.stream.encodeChild(v)
+	}
 	e.buffer, err = Marshal(v, e.buffer)
 	if err == nil {
 		_, err = e.writer.Write(e.buffer)
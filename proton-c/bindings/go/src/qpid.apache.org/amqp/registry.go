@@ -0,0 +1,151 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package amqp
+
+// #include <proton/codec.h>
+import "C"
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// describedRegistry records the bidirectional mapping between AMQP
+// descriptors and Go types established by RegisterDescribed, guarded by a
+// single RWMutex since registration is rare but lookup happens on every
+// marshal/unmarshal of a registered type.
+var describedRegistry = struct {
+	sync.RWMutex
+	byGoType     map[reflect.Type]interface{}
+	byDescriptor map[interface{}]reflect.Type
+}{
+	byGoType:     make(map[reflect.Type]interface{}),
+	byDescriptor: make(map[interface{}]reflect.Type),
+}
+
+// RegisterDescribed records that values of prototype's Go type (prototype
+// may be a struct value or a pointer to one) marshal as an AMQP described
+// type with the given descriptor, and that unmarshaling a described value
+// with that descriptor should produce a new instance of that Go type. The
+// descriptor must be a ulong code (any Go integer type) or a string/Symbol;
+// it is always stored and compared as a uint64 or Symbol.
+//
+// This lets callers declare a domain type once and have it travel across
+// links without hand-written Described{...} wrappers at every call site,
+// mirroring how AMQP composite types (delivery annotations, filter sets,
+// application-defined types) are normally conveyed.
+//
+// RegisterDescribed returns an error if the Go type or the descriptor is
+// already registered. It is safe to call concurrently.
+func RegisterDescribed(descriptor interface{}, prototype interface{}) error {
+	d, err := normalizeDescriptor(descriptor)
+	if err != nil {
+		return err
+	}
+	t := reflect.TypeOf(prototype)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	describedRegistry.Lock()
+	defer describedRegistry.Unlock()
+	if _, dup := describedRegistry.byGoType[t]; dup {
+		return fmt.Errorf("amqp: %s is already registered as a described type", t)
+	}
+	if _, dup := describedRegistry.byDescriptor[d]; dup {
+		return fmt.Errorf("amqp: descriptor %v is already registered", d)
+	}
+	describedRegistry.byGoType[t] = d
+	describedRegistry.byDescriptor[d] = t
+	return nil
+}
+
+// MustRegisterDescribed is like RegisterDescribed but panics on error, for
+// use in package init() functions.
+func MustRegisterDescribed(descriptor interface{}, prototype interface{}) {
+	if err := RegisterDescribed(descriptor, prototype); err != nil {
+		panic(err)
+	}
+}
+
+// normalizeDescriptor converts descriptor to the canonical comparable form
+// used as a registry key: uint64 for any integer type, Symbol for a string
+// or Symbol. Other string-kinded types are rejected.
+func normalizeDescriptor(descriptor interface{}) (interface{}, error) {
+	switch d := descriptor.(type) {
+	case Symbol:
+		return d, nil
+	case string:
+		return Symbol(d), nil
+	case uint64:
+		return d, nil
+	}
+	rv := reflect.ValueOf(descriptor)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	default:
+		return nil, fmt.Errorf("amqp: invalid descriptor %#v, must be a ulong code or a string/Symbol", descriptor)
+	}
+}
+
+// descriptorFor returns the registered descriptor for t, if any.
+func descriptorFor(t reflect.Type) (interface{}, bool) {
+	describedRegistry.RLock()
+	defer describedRegistry.RUnlock()
+	d, ok := describedRegistry.byGoType[t]
+	return d, ok
+}
+
+// goTypeFor returns the registered Go type for descriptor, if any.
+func goTypeFor(descriptor interface{}) (reflect.Type, bool) {
+	d, err := normalizeDescriptor(descriptor)
+	if err != nil {
+		return nil, false
+	}
+	describedRegistry.RLock()
+	defer describedRegistry.RUnlock()
+	t, ok := describedRegistry.byDescriptor[d]
+	return t, ok
+}
+
+// marshalDescribedBody encodes v's value as the body of a described type,
+// reusing the struct-field plan from struct marshaling for struct values, or
+// falling back to ordinary map, list/array or primitive emission for
+// anything else.
+func marshalDescribedBody(data *C.pn_data_t, v interface{}) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Struct:
+		putFieldList(data, planFor(rv.Type()), rv)
+	case reflect.Map:
+		putMap(data, v)
+	case reflect.Slice:
+		putSlice(data, v)
+	default:
+		c := canonicalElement(rv)
+		if reflect.TypeOf(c) == rv.Type() {
+			panic(newMarshalError(v, "no conversion"))
+		}
+		marshal(c, data)
+	}
+}
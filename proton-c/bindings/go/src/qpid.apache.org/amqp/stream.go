@@ -0,0 +1,233 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package amqp
+
+// #include <proton/codec.h>
+import "C"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Container format codes, see the AMQP 1.0 spec section 1.6.22-24. The
+// streaming encoder always uses the 4-byte size/count form, even for small
+// containers, to avoid a second pass to pick the compact 1-byte form.
+const (
+	listFormatCode = 0xd0
+	mapFormatCode  = 0xd1
+)
+
+// streamState tracks an in-progress EncodeListStream/EncodeMapStream/
+// EncodeArrayStream call.
+//
+// For EncodeListStream/EncodeMapStream, each child is marshaled into its own
+// *C.pn_data_t, encoded into a reusable scratch buffer, and the data cleared
+// again before the next child is produced, so at most one child's encoded
+// form is ever held in memory; arrayMode is false and data holds just the
+// current child.
+//
+// For EncodeArrayStream, data is instead the single shared pn_data_t for the
+// whole array (entered once by EncodeArrayStream itself): an AMQP array's
+// element constructor is written only once for the whole array, which is
+// only possible by building the array as one tree, the same way the
+// non-streaming putArray does. arrayMode is true and Encode just marshals
+// each child into that shared tree; nothing is written to writer until the
+// whole array has been produced.
+type streamState struct {
+	data        *C.pn_data_t
+	scratch     []byte
+	writer      io.Writer
+	calls, want int
+	elementType AMQPType // only checked for EncodeArrayStream
+	measuring   bool     // list/map only: true during the size-measuring pass
+	total       int
+	arrayMode   bool
+}
+
+func (s *streamState) encodeChild(v interface{}) error {
+	s.calls++
+	if s.calls > s.want {
+		return fmt.Errorf("amqp: stream callback called Encode more than the expected %d times", s.want)
+	}
+	if s.arrayMode {
+		marshal(v, s.data)
+		if err := dataMarshalError(v, s.data); err != nil {
+			return err
+		}
+		if AMQPType(C.pn_data_type(s.data)) != s.elementType {
+			return fmt.Errorf("amqp: array stream element has type %s, want %s", AMQPType(C.pn_data_type(s.data)), s.elementType)
+		}
+		return nil
+	}
+	C.pn_data_clear(s.data)
+	marshal(v, s.data)
+	if err := dataMarshalError(v, s.data); err != nil {
+		return err
+	}
+	n := int(C.pn_data_encode(s.data, cPtr(s.scratch), cLen(s.scratch)))
+	for n == int(C.PN_OVERFLOW) {
+		s.scratch = make([]byte, 2*len(s.scratch))
+		n = int(C.pn_data_encode(s.data, cPtr(s.scratch), cLen(s.scratch)))
+	}
+	if n < 0 {
+		return dataMarshalError(v, s.data)
+	}
+	if s.measuring {
+		s.total += n
+		return nil
+	}
+	_, err := s.writer.Write(s.scratch[:n])
+	return err
+}
+
+// EncodeListStream encodes an AMQP list of n elements without holding the
+// whole encoded list in memory at once, unlike Encode(List{...}). fn is
+// called to emit the elements; it must call Encode on the *Encoder it is
+// passed exactly n times, in order.
+//
+// Because a list's AMQP framing records the total encoded size of its
+// elements ahead of the elements themselves, fn is invoked twice: once to
+// measure each element's encoded size (those bytes are discarded), and once
+// more to write them to the underlying io.Writer. fn must therefore be
+// deterministic and have no side effects other than the Encode calls it
+// makes. If fn calls Encode a number of times other than n, EncodeListStream
+// returns a descriptive error.
+//
+// This two-pass replay is a real constraint on the kind of source fn can
+// wrap: it works for anything re-iterable (a slice, a re-openable cursor),
+// but not for a one-shot source such as a channel, since its elements can't
+// be produced a second time for the write pass. The same applies to
+// EncodeMapStream below.
+func (e *Encoder) EncodeListStream(n int, fn func(*Encoder) error) error {
+	return e.encodeStream(listFormatCode, n, fn)
+}
+
+// EncodeMapStream is like EncodeListStream but encodes an AMQP map of n
+// key/value pairs; fn must call Encode exactly 2*n times (key, value, key,
+// value, ...). The AMQP wire count for a map is the number of encoded
+// values, i.e. 2*n, not the number of pairs.
+func (e *Encoder) EncodeMapStream(n int, fn func(*Encoder) error) error {
+	return e.encodeStream(mapFormatCode, 2*n, fn)
+}
+
+// EncodeArrayStream encodes an AMQP array of n elements of elementType; fn
+// must call Encode exactly n times, and each value it encodes must have
+// AMQP type elementType.
+//
+// Unlike EncodeListStream/EncodeMapStream, this does not encode at constant
+// memory: an AMQP array's element constructor is written once for the whole
+// array (see pn_data_put_array), which cannot be produced by independently
+// encoding each element and concatenating the bytes the way list and map
+// elements can. EncodeArrayStream instead builds the whole array in one
+// pn_data_t, exactly as the non-streaming Array type does via putArray, and
+// exists so callers can drive array construction through the same
+// Encode-per-element call shape as the other EncodeXStream methods.
+func (e *Encoder) EncodeArrayStream(n int, elementType AMQPType, fn func(*Encoder) error) (err error) {
+	if e.stream != nil {
+		return fmt.Errorf("amqp: EncodeListStream/EncodeMapStream/EncodeArrayStream calls cannot be nested")
+	}
+	data := C.pn_data(0)
+	defer C.pn_data_free(data)
+	C.pn_data_put_array(data, C.bool(false), C.pn_type_t(elementType))
+	C.pn_data_enter(data)
+	s := &streamState{data: data, want: n, elementType: elementType, arrayMode: true}
+	e.stream = s
+	err = fn(e)
+	e.stream = nil
+	if err != nil {
+		return err
+	}
+	if s.calls != n {
+		return fmt.Errorf("amqp: array stream callback called Encode %d times, want %d", s.calls, n)
+	}
+	C.pn_data_exit(data)
+	buf := make([]byte, minEncode)
+	encode := func(buf []byte) ([]byte, error) {
+		written := int(C.pn_data_encode(data, cPtr(buf), cLen(buf)))
+		switch {
+		case written == int(C.PN_OVERFLOW):
+			return buf, overflow
+		case written < 0:
+			return buf, dataMarshalError(nil, data)
+		default:
+			return buf[:written], nil
+		}
+	}
+	if buf, err = encodeGrow(buf, encode); err != nil {
+		return err
+	}
+	_, err = e.writer.Write(buf)
+	return err
+}
+
+// encodeStream drives EncodeListStream/EncodeMapStream: fn is invoked twice,
+// once to measure the encoded size of each element (discarding the bytes)
+// and once more to write the container header followed by each element.
+// wireCount is the value written into the AMQP wire "count" field, and is
+// also the number of times fn must call Encode.
+func (e *Encoder) encodeStream(formatCode byte, wireCount int, fn func(*Encoder) error) (err error) {
+	if e.stream != nil {
+		return fmt.Errorf("amqp: EncodeListStream/EncodeMapStream/EncodeArrayStream calls cannot be nested")
+	}
+	measure := C.pn_data(0)
+	defer C.pn_data_free(measure)
+	e.stream = &streamState{data: measure, scratch: make([]byte, minEncode), want: wireCount, measuring: true}
+	err = fn(e)
+	size := e.stream.total
+	calls := e.stream.calls
+	e.stream = nil
+	if err != nil {
+		return err
+	}
+	if calls != wireCount {
+		return fmt.Errorf("amqp: stream callback called Encode %d times, want %d", calls, wireCount)
+	}
+	if err = writeContainerHeader(e.writer, formatCode, wireCount, size); err != nil {
+		return err
+	}
+	data := C.pn_data(0)
+	defer C.pn_data_free(data)
+	s := &streamState{data: data, scratch: make([]byte, minEncode), writer: e.writer, want: wireCount}
+	e.stream = s
+	err = fn(e)
+	e.stream = nil
+	if err != nil {
+		return err
+	}
+	if s.calls != wireCount {
+		return fmt.Errorf("amqp: stream callback called Encode %d times, want %d", s.calls, wireCount)
+	}
+	return nil
+}
+
+// writeContainerHeader writes the format code, 4-byte size and 4-byte count
+// of a list32/map32 container. size is the number of bytes already measured
+// for the elements; the on-wire size field also includes the 4-byte count
+// field itself.
+func writeContainerHeader(w io.Writer, formatCode byte, count, size int) error {
+	var header [9]byte
+	header[0] = formatCode
+	binary.BigEndian.PutUint32(header[1:5], uint32(size+4))
+	binary.BigEndian.PutUint32(header[5:9], uint32(count))
+	_, err := w.Write(header[:])
+	return err
+}
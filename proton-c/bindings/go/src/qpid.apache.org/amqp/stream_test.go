@@ -0,0 +1,178 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package amqp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeListStreamRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	values := []interface{}{int32(1), "two", Symbol("three")}
+	err := e.EncodeListStream(len(values), func(e *Encoder) error {
+		for _, v := range values {
+			if err := e.Encode(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EncodeListStream: %v", err)
+	}
+	var got interface{}
+	if _, err := Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	want := List{int32(1), "two", Symbol("three")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestEncodeMapStreamRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	pairs := []interface{}{"a", int32(1), "b", int32(2)}
+	err := e.EncodeMapStream(len(pairs)/2, func(e *Encoder) error {
+		for _, v := range pairs {
+			if err := e.Encode(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EncodeMapStream: %v", err)
+	}
+	var got interface{}
+	if _, err := Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	want := Map{"a": int32(1), "b": int32(2)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestEncodeArrayStreamRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	values := []int32{1, 2, 3}
+	err := e.EncodeArrayStream(len(values), Int, func(e *Encoder) error {
+		for _, v := range values {
+			if err := e.Encode(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EncodeArrayStream: %v", err)
+	}
+	var got interface{}
+	if _, err := Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	array, ok := got.(Array)
+	if !ok {
+		t.Fatalf("Unmarshal = %#v, want Array", got)
+	}
+	if array.ElementType != Int {
+		t.Errorf("ElementType = %v, want Int", array.ElementType)
+	}
+	if !reflect.DeepEqual(array.Values, []int32{1, 2, 3}) {
+		t.Errorf("Values = %#v, want [1 2 3]", array.Values)
+	}
+}
+
+func TestEncodeListStreamWrongCallCount(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	err := e.EncodeListStream(2, func(e *Encoder) error {
+		return e.Encode(int32(1)) // only 1 of the promised 2 calls
+	})
+	if err == nil {
+		t.Fatal("EncodeListStream: want error for wrong call count, got nil")
+	}
+}
+
+func TestEncodeListStreamTooManyCalls(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	err := e.EncodeListStream(1, func(e *Encoder) error {
+		if err := e.Encode(int32(1)); err != nil {
+			return err
+		}
+		return e.Encode(int32(2)) // exceeds the promised 1 call
+	})
+	if err == nil {
+		t.Fatal("EncodeListStream: want error for too many calls, got nil")
+	}
+}
+
+func TestEncodeMapStreamWrongCallCount(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	err := e.EncodeMapStream(2, func(e *Encoder) error {
+		return e.Encode("only-a-key") // 1 call instead of the promised 2*n == 4
+	})
+	if err == nil {
+		t.Fatal("EncodeMapStream: want error for wrong call count, got nil")
+	}
+}
+
+func TestEncodeArrayStreamWrongCallCount(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	err := e.EncodeArrayStream(3, Int, func(e *Encoder) error {
+		return e.Encode(int32(1)) // only 1 of the promised 3 calls
+	})
+	if err == nil {
+		t.Fatal("EncodeArrayStream: want error for wrong call count, got nil")
+	}
+}
+
+func TestEncodeArrayStreamElementTypeMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	err := e.EncodeArrayStream(1, Int, func(e *Encoder) error {
+		return e.Encode("not an int")
+	})
+	if err == nil {
+		t.Fatal("EncodeArrayStream: want error for element type mismatch, got nil")
+	}
+}
+
+func TestEncodeStreamCannotNest(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	err := e.EncodeListStream(1, func(e *Encoder) error {
+		return e.EncodeListStream(1, func(e *Encoder) error {
+			return e.Encode(int32(1))
+		})
+	})
+	if err == nil {
+		t.Fatal("nested EncodeListStream: want error, got nil")
+	}
+}
@@ -0,0 +1,294 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package amqp
+
+// #include <proton/codec.h>
+import "C"
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Struct fields are marshaled using an `amqp` tag of the form
+//
+//	amqp:"name,omitempty,symbol"
+//
+// name overrides the map key (defaults to the field name), omitempty drops
+// the field when it holds its zero value, and symbol encodes the key as a
+// Symbol rather than a string. A tag of "-" skips the field entirely.
+//
+// A struct-level descriptor is declared by tagging one field (its own name
+// and type are ignored) with amqp:",described=<descriptor>": the struct is
+// then marshaled as a Described value with that field's tag value as the
+// descriptor (a Symbol unless it parses as an unsigned integer, in which
+// case it is a ulong) and the remaining fields as the list body, matching
+// how AMQP composite types such as message properties are conveyed.
+
+// fieldInfo describes how to encode/decode one struct field.
+type fieldInfo struct {
+	index     []int
+	name      string
+	omitEmpty bool
+	symbol    bool
+}
+
+// structPlan is the cached encode/decode plan for one struct type.
+type structPlan struct {
+	fields     []fieldInfo
+	described  bool
+	descriptor interface{}
+}
+
+var structPlans = struct {
+	sync.RWMutex
+	m map[reflect.Type]*structPlan
+}{m: make(map[reflect.Type]*structPlan)}
+
+// planFor returns the structPlan for t, building and caching it on first use.
+func planFor(t reflect.Type) *structPlan {
+	structPlans.RLock()
+	plan, ok := structPlans.m[t]
+	structPlans.RUnlock()
+	if ok {
+		return plan
+	}
+	plan = &structPlan{}
+	addFields(plan, t, nil)
+	structPlans.Lock()
+	structPlans.m[t] = plan
+	structPlans.Unlock()
+	return plan
+}
+
+func addFields(plan *structPlan, t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous { // unexported
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+		tag := f.Tag.Get("amqp")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+		var omitEmpty, symbol, isMarker bool
+		for _, opt := range opts[1:] {
+			switch {
+			case opt == "omitempty":
+				omitEmpty = true
+			case opt == "symbol":
+				symbol = true
+			case strings.HasPrefix(opt, "described="):
+				isMarker = true
+				plan.described = true
+				plan.descriptor = parseDescriptor(strings.TrimPrefix(opt, "described="))
+			}
+		}
+		if isMarker {
+			continue // marker field, carries no value of its own, regardless of where described= appears in the tag
+		}
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(plan, ft, fieldIndex)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		plan.fields = append(plan.fields, fieldInfo{index: fieldIndex, name: name, omitEmpty: omitEmpty, symbol: symbol})
+	}
+}
+
+// parseDescriptor converts a described= tag value to a ulong if it is all
+// digits, otherwise to a Symbol.
+func parseDescriptor(s string) interface{} {
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n
+	}
+	return Symbol(s)
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Array, reflect.Struct:
+		return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+	default:
+		return false
+	}
+}
+
+// marshalStruct encodes v (a struct) as a Described list or a map, per its plan.
+func marshalStruct(data *C.pn_data_t, v interface{}) {
+	rv := reflect.ValueOf(v)
+	plan := planFor(rv.Type())
+	if plan.described {
+		C.pn_data_put_described(data)
+		C.pn_data_enter(data)
+		marshal(plan.descriptor, data)
+		putFieldList(data, plan, rv)
+		C.pn_data_exit(data)
+		return
+	}
+	putFieldMap(data, plan, rv)
+}
+
+// putFieldList encodes the struct's fields as a positional list body,
+// dropping trailing omitempty fields that hold their zero value.
+func putFieldList(data *C.pn_data_t, plan *structPlan, rv reflect.Value) {
+	last := len(plan.fields)
+	for last > 0 {
+		fi := plan.fields[last-1]
+		if fi.omitEmpty && isZero(rv.FieldByIndex(fi.index)) {
+			last--
+			continue
+		}
+		break
+	}
+	C.pn_data_put_list(data)
+	C.pn_data_enter(data)
+	for _, fi := range plan.fields[:last] {
+		fv := rv.FieldByIndex(fi.index)
+		if fi.omitEmpty && isZero(fv) {
+			marshal(nil, data)
+			continue
+		}
+		putField(data, fv)
+	}
+	C.pn_data_exit(data)
+}
+
+// putFieldMap encodes the struct's fields as a map, omitting omitempty
+// fields that hold their zero value.
+func putFieldMap(data *C.pn_data_t, plan *structPlan, rv reflect.Value) {
+	C.pn_data_put_map(data)
+	C.pn_data_enter(data)
+	for _, fi := range plan.fields {
+		fv := rv.FieldByIndex(fi.index)
+		if fi.omitEmpty && isZero(fv) {
+			continue
+		}
+		if fi.symbol {
+			marshal(Symbol(fi.name), data)
+		} else {
+			marshal(fi.name, data)
+		}
+		putField(data, fv)
+	}
+	C.pn_data_exit(data)
+}
+
+func putField(data *C.pn_data_t, fv reflect.Value) {
+	for fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			marshal(nil, data)
+			return
+		}
+		fv = fv.Elem()
+	}
+	marshal(fv.Interface(), data)
+}
+
+// unmarshalStructFields decodes the map or list value currently pointed to
+// by data's cursor into target, which must be a struct.
+func unmarshalStructFields(target reflect.Value, data *C.pn_data_t) {
+	switch C.pn_data_type(data) {
+	case C.PN_MAP:
+		unmarshalStructMap(target, data)
+	case C.PN_LIST:
+		unmarshalStructList(target, data)
+	}
+}
+
+func unmarshalStructMap(target reflect.Value, data *C.pn_data_t) {
+	plan := planFor(target.Type())
+	byName := make(map[string]fieldInfo, len(plan.fields))
+	for _, fi := range plan.fields {
+		byName[fi.name] = fi
+	}
+	C.pn_data_enter(data)
+	for C.pn_data_next(data) != 0 {
+		var name string
+		switch key := get(data).(type) {
+		case string:
+			name = key
+		case Symbol:
+			name = string(key)
+		}
+		if C.pn_data_next(data) == 0 {
+			break
+		}
+		if fi, ok := byName[name]; ok {
+			set(fieldValue(target, fi), data)
+		}
+	}
+	C.pn_data_exit(data)
+}
+
+func unmarshalStructList(target reflect.Value, data *C.pn_data_t) {
+	plan := planFor(target.Type())
+	C.pn_data_enter(data)
+	for _, fi := range plan.fields {
+		if C.pn_data_next(data) == 0 {
+			break // trailing fields omitted
+		}
+		set(fieldValue(target, fi), data)
+	}
+	C.pn_data_exit(data)
+}
+
+// fieldValue returns the addressable field at fi.index, allocating nil
+// pointers along the way.
+func fieldValue(target reflect.Value, fi fieldInfo) reflect.Value {
+	v := target
+	for _, i := range fi.index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
@@ -0,0 +1,171 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package amqp
+
+import (
+	"testing"
+	"time"
+)
+
+type structTestAddress struct {
+	Street string
+	City   string `amqp:",omitempty"`
+}
+
+type structTestPerson struct {
+	Name    string
+	Age     int32              `amqp:",omitempty"`
+	Address *structTestAddress `amqp:",omitempty"`
+}
+
+func TestStructNestedAndPointerRoundTrip(t *testing.T) {
+	want := structTestPerson{
+		Name: "Alice",
+		Age:  30,
+		Address: &structTestAddress{
+			Street: "1 Main St",
+			City:   "Springfield",
+		},
+	}
+	bytes, err := Marshal(want, nil)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got structTestPerson
+	if _, err := Unmarshal(bytes, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got.Name != want.Name || got.Age != want.Age {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if got.Address == nil || *got.Address != *want.Address {
+		t.Fatalf("got.Address = %+v, want %+v", got.Address, want.Address)
+	}
+}
+
+func TestStructNilPointerOmitted(t *testing.T) {
+	want := structTestPerson{Name: "Bob"}
+	bytes, err := Marshal(want, nil)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got structTestPerson
+	if _, err := Unmarshal(bytes, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got.Name != "Bob" || got.Age != 0 || got.Address != nil {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+// isZero must recognize the zero value of fixed-size array kinds (UUID,
+// Decimal128) and struct kinds (time.Time, nested structs) for omitempty to
+// drop them; otherwise they are always encoded even when zero.
+type structTestTimestamped struct {
+	ID      UUID      `amqp:",omitempty"`
+	Created time.Time `amqp:",omitempty"`
+}
+
+func TestStructOmitEmptyArrayAndStructFields(t *testing.T) {
+	bytes, err := Marshal(structTestTimestamped{}, nil)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got interface{}
+	if _, err := Unmarshal(bytes, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	m, ok := got.(Map)
+	if !ok || len(m) != 0 {
+		t.Fatalf("Marshal(zero structTestTimestamped) = %#v, want an empty map", got)
+	}
+
+	nonZero := structTestTimestamped{ID: UUID{1}, Created: time.Unix(1000, 0)}
+	bytes, err = Marshal(nonZero, nil)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	got = nil
+	if _, err := Unmarshal(bytes, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	m, ok = got.(Map)
+	if !ok || len(m) != 2 {
+		t.Fatalf("Marshal(non-zero structTestTimestamped) = %#v, want a 2-key map", got)
+	}
+}
+
+type structTestBase struct {
+	ID string
+}
+
+type structTestEmbedded struct {
+	structTestBase
+	Name string
+}
+
+func TestStructEmbeddedFieldRoundTrip(t *testing.T) {
+	want := structTestEmbedded{structTestBase{ID: "id-1"}, "widget"}
+	bytes, err := Marshal(want, nil)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got structTestEmbedded
+	if _, err := Unmarshal(bytes, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+// A struct with a described= marker field marshals as a Described list,
+// regardless of where described= falls among the field's other tag options.
+type structTestDescribedFirst struct {
+	Marker struct{} `amqp:",described=48,omitempty"`
+	Name   string
+	Count  int32 `amqp:",omitempty"`
+}
+
+func TestStructDescribedMarkerNotLastOption(t *testing.T) {
+	want := structTestDescribedFirst{Name: "thing", Count: 5}
+	bytes, err := Marshal(want, nil)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got interface{}
+	if _, err := Unmarshal(bytes, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	described, ok := got.(Described)
+	if !ok {
+		t.Fatalf("Unmarshal = %#v, want Described", got)
+	}
+	if described.Descriptor != uint64(48) {
+		t.Errorf("Descriptor = %#v, want uint64(48)", described.Descriptor)
+	}
+	list, ok := described.Value.(List)
+	if !ok || len(list) != 2 {
+		t.Fatalf("Value = %#v, want a 2-element List (marker field must not appear)", described.Value)
+	}
+	if list[0] != "thing" || list[1] != int32(5) {
+		t.Errorf("Value = %#v, want [thing 5]", list)
+	}
+}
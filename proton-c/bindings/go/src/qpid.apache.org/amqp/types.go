@@ -0,0 +1,179 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package amqp
+
+// #include <proton/codec.h>
+// #include <proton/error.h>
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Symbol is a string that is encoded as an AMQP symbol
+type Symbol string
+
+// Char is a unicode character, encoded as an AMQP char.
+type Char rune
+
+// Binary is a string of bytes that is encoded as an AMQP binary value.
+//
+// We use string rather than []byte to allow Binary values to be used as map keys.
+type Binary string
+
+// UUID is a 16-byte universally unique identifier, encoded as an AMQP uuid.
+type UUID [16]byte
+
+// Decimal32 holds the bits of an AMQP decimal32 value.
+type Decimal32 uint32
+
+// Decimal64 holds the bits of an AMQP decimal64 value.
+type Decimal64 uint64
+
+// Decimal128 holds the bytes of an AMQP decimal128 value.
+type Decimal128 [16]byte
+
+// Map is a generic map that can hold mixed key and value types, encoded as an AMQP map.
+type Map map[interface{}]interface{}
+
+// List is a generic list that can hold mixed value types, encoded as an AMQP list.
+type List []interface{}
+
+// Array is an AMQP array: a sequence of values that all share a single
+// element type, encoded with one element constructor rather than one per
+// value as AMQP list does. ElementType identifies the AMQP type of each
+// element, Values holds them as a Go slice whose element type corresponds to
+// ElementType (e.g. ElementType Int pairs with Values []int32).
+//
+// An ordinary Go slice (e.g. []int32, []string, []Symbol) whose element type
+// maps to an AMQP primitive is marshaled as an Array automatically, except a
+// slice whose element kind is int or uint, which is marshaled as a list; Array is
+// needed only to pick an element type explicitly, or for element types with
+// no single obvious Go representation.
+type Array struct {
+	ElementType AMQPType
+	Values      interface{}
+}
+
+// Described is an AMQP described type: a Descriptor and a Value.
+type Described struct {
+	Descriptor interface{}
+	Value      interface{}
+}
+
+// AnnotationKey is a key for an annotation map, which may be a Symbol or a uint64.
+type AnnotationKey struct {
+	String string
+	Uint64 uint64
+}
+
+// Get returns the key value as a Symbol if String is set, otherwise as a uint64.
+func (a AnnotationKey) Get() interface{} {
+	if a.String != "" {
+		return Symbol(a.String)
+	}
+	return a.Uint64
+}
+
+// AMQPType identifies an AMQP type, corresponding to the pn_type_t values.
+type AMQPType C.pn_type_t
+
+const (
+	Null           AMQPType = C.PN_NULL
+	Bool           AMQPType = C.PN_BOOL
+	Ubyte          AMQPType = C.PN_UBYTE
+	Byte           AMQPType = C.PN_BYTE
+	Ushort         AMQPType = C.PN_USHORT
+	Short          AMQPType = C.PN_SHORT
+	Uint           AMQPType = C.PN_UINT
+	Int            AMQPType = C.PN_INT
+	Char_          AMQPType = C.PN_CHAR
+	Ulong          AMQPType = C.PN_ULONG
+	Long           AMQPType = C.PN_LONG
+	Timestamp      AMQPType = C.PN_TIMESTAMP
+	Float          AMQPType = C.PN_FLOAT
+	Double         AMQPType = C.PN_DOUBLE
+	Decimal32Type  AMQPType = C.PN_DECIMAL32
+	Decimal64Type  AMQPType = C.PN_DECIMAL64
+	Decimal128Type AMQPType = C.PN_DECIMAL128
+	UuidType       AMQPType = C.PN_UUID
+	Binary_        AMQPType = C.PN_BINARY
+	String_        AMQPType = C.PN_STRING
+	SymbolType     AMQPType = C.PN_SYMBOL
+	Described_     AMQPType = C.PN_DESCRIBED
+	Array_         AMQPType = C.PN_ARRAY
+	List_          AMQPType = C.PN_LIST
+	Map_           AMQPType = C.PN_MAP
+)
+
+func (t AMQPType) String() string {
+	return C.GoString(C.pn_type_name(C.pn_type_t(t)))
+}
+
+// PnError converts a pn_error_t* to a Go error, or nil if there is no error.
+func PnError(e *C.pn_error_t) error {
+	if e == nil || C.pn_error_code(e) == 0 {
+		return nil
+	}
+	return fmt.Errorf(C.GoString(C.pn_error_text(e)))
+}
+
+// cPtr returns a pointer to the first byte of b, or nil if b is empty.
+func cPtr(b []byte) *C.char {
+	if len(b) == 0 {
+		return nil
+	}
+	return (*C.char)(unsafe.Pointer(&b[0]))
+}
+
+// cLen returns the length of b as a C.size_t.
+func cLen(b []byte) C.size_t {
+	return C.size_t(len(b))
+}
+
+// pnBytes wraps a []byte as a pn_bytes_t without copying.
+func pnBytes(b []byte) C.pn_bytes_t {
+	return C.pn_bytes(cLen(b), cPtr(b))
+}
+
+// goBytes copies a pn_bytes_t into a new []byte.
+func goBytes(b C.pn_bytes_t) []byte {
+	return C.GoBytes(unsafe.Pointer(b.start), C.int(b.size))
+}
+
+// goString copies a pn_bytes_t into a new Go string.
+func goString(b C.pn_bytes_t) string {
+	return C.GoStringN(b.start, C.int(b.size))
+}
+
+// goUUID converts a pn_uuid_t into a UUID.
+func goUUID(u C.pn_uuid_t) UUID {
+	var id UUID
+	copy(id[:], C.GoBytes(unsafe.Pointer(&u.bytes[0]), 16))
+	return id
+}
+
+// goDecimal128 converts a pn_decimal128_t into a Decimal128.
+func goDecimal128(d C.pn_decimal128_t) Decimal128 {
+	var v Decimal128
+	copy(v[:], C.GoBytes(unsafe.Pointer(&d.bytes[0]), 16))
+	return v
+}
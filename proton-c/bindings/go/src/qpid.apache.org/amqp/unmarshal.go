@@ -0,0 +1,324 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package amqp
+
+// #include <proton/codec.h>
+import "C"
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Error returned if AMQP data cannot be unmarshaled into a Go value.
+type UnmarshalError struct {
+	// The AMQP type.
+	AMQPType AMQPType
+	// The Go type.
+	GoType reflect.Type
+	s      string
+}
+
+func (e UnmarshalError) Error() string { return e.s }
+
+func newUnmarshalError(pnType C.pn_type_t, v interface{}) *UnmarshalError {
+	t := reflect.TypeOf(v)
+	return &UnmarshalError{
+		AMQPType: AMQPType(pnType),
+		GoType:   t,
+		s:        fmt.Sprintf("cannot unmarshal AMQP %s to %s", AMQPType(pnType), t),
+	}
+}
+
+func recoverUnmarshal(err *error) {
+	if r := recover(); r != nil {
+		if uerr, ok := r.(*UnmarshalError); ok {
+			*err = uerr
+		} else {
+			panic(r)
+		}
+	}
+}
+
+// Unmarshal decodes AMQP data into the value pointed to by v, which must be
+// a non-nil pointer. Returns the number of bytes consumed from bytes.
+//
+// v may point to interface{} to receive whatever concrete Go type Marshal
+// would have produced for the encoded value (map, list, Array etc.), or to a
+// specific type to decode directly into it.
+func Unmarshal(bytes []byte, v interface{}) (n int, err error) {
+	defer recoverUnmarshal(&err)
+	data := C.pn_data(0)
+	defer C.pn_data_free(data)
+	n = int(C.pn_data_decode(data, cPtr(bytes), cLen(bytes)))
+	if n < 0 {
+		return 0, dataUnmarshalError(v, data)
+	}
+	C.pn_data_rewind(data)
+	if C.pn_data_next(data) == 0 {
+		return n, dataUnmarshalError(v, data)
+	}
+	unmarshal(v, data)
+	return n, nil
+}
+
+func dataUnmarshalError(v interface{}, data *C.pn_data_t) error {
+	if pe := PnError(C.pn_data_error(data)); pe != nil {
+		return fmt.Errorf("cannot unmarshal %T: %s", v, pe)
+	}
+	return nil
+}
+
+// unmarshal decodes the value currently pointed to by data's cursor into the
+// value pointed to by v.
+func unmarshal(v interface{}, data *C.pn_data_t) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		panic(newUnmarshalError(C.pn_data_type(data), v))
+	}
+	set(rv.Elem(), data)
+}
+
+// get decodes the value currently pointed to by data's cursor as an
+// interface{} holding the same concrete Go type Marshal would have produced.
+func get(data *C.pn_data_t) interface{} {
+	var v interface{}
+	set(reflect.ValueOf(&v).Elem(), data)
+	return v
+}
+
+// set decodes the value currently pointed to by data's cursor into target,
+// converting to target's concrete type if it is not an interface{}. If
+// target is a pointer, it is allocated (unless the AMQP value is null, in
+// which case target is simply set to nil) and the value is decoded into the
+// pointed-to value instead, mirroring how Marshal dereferences pointer
+// fields (see putField).
+func set(target reflect.Value, data *C.pn_data_t) {
+	pnType := C.pn_data_type(data)
+	if target.Kind() == reflect.Ptr && pnType != C.PN_NULL {
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		set(target.Elem(), data)
+		return
+	}
+	switch pnType {
+	case C.PN_NULL:
+		target.Set(reflect.Zero(target.Type()))
+		return
+	case C.PN_BOOL:
+		setValue(target, pnType, bool(C.pn_data_get_bool(data)))
+	case C.PN_UBYTE:
+		setValue(target, pnType, uint8(C.pn_data_get_ubyte(data)))
+	case C.PN_BYTE:
+		setValue(target, pnType, int8(C.pn_data_get_byte(data)))
+	case C.PN_USHORT:
+		setValue(target, pnType, uint16(C.pn_data_get_ushort(data)))
+	case C.PN_SHORT:
+		setValue(target, pnType, int16(C.pn_data_get_short(data)))
+	case C.PN_UINT:
+		setValue(target, pnType, uint32(C.pn_data_get_uint(data)))
+	case C.PN_INT:
+		setValue(target, pnType, int32(C.pn_data_get_int(data)))
+	case C.PN_CHAR:
+		setValue(target, pnType, Char(C.pn_data_get_char(data)))
+	case C.PN_ULONG:
+		setValue(target, pnType, uint64(C.pn_data_get_ulong(data)))
+	case C.PN_LONG:
+		setValue(target, pnType, int64(C.pn_data_get_long(data)))
+	case C.PN_TIMESTAMP:
+		setValue(target, pnType, time.Unix(0, int64(C.pn_data_get_timestamp(data))*1000))
+	case C.PN_FLOAT:
+		setValue(target, pnType, float32(C.pn_data_get_float(data)))
+	case C.PN_DOUBLE:
+		setValue(target, pnType, float64(C.pn_data_get_double(data)))
+	case C.PN_DECIMAL32:
+		setValue(target, pnType, Decimal32(C.pn_data_get_decimal32(data)))
+	case C.PN_DECIMAL64:
+		setValue(target, pnType, Decimal64(C.pn_data_get_decimal64(data)))
+	case C.PN_DECIMAL128:
+		setValue(target, pnType, goDecimal128(C.pn_data_get_decimal128(data)))
+	case C.PN_STRING:
+		setValue(target, pnType, goString(C.pn_data_get_string(data)))
+	case C.PN_SYMBOL:
+		setValue(target, pnType, Symbol(goString(C.pn_data_get_symbol(data))))
+	case C.PN_BINARY:
+		setValue(target, pnType, Binary(goString(C.pn_data_get_binary(data))))
+	case C.PN_UUID:
+		setValue(target, pnType, goUUID(C.pn_data_get_uuid(data)))
+	case C.PN_LIST:
+		if target.Kind() == reflect.Struct {
+			unmarshalStructFields(target, data)
+		} else {
+			setValue(target, pnType, getList(data))
+		}
+	case C.PN_MAP:
+		if target.Kind() == reflect.Struct {
+			unmarshalStructFields(target, data)
+		} else {
+			setValue(target, pnType, getMap(data))
+		}
+	case C.PN_ARRAY:
+		setValue(target, pnType, getArray(data))
+	case C.PN_DESCRIBED:
+		if target.Kind() == reflect.Struct {
+			C.pn_data_enter(data)
+			C.pn_data_next(data)
+			get(data) // descriptor; struct fields are matched positionally/by name instead
+			C.pn_data_next(data)
+			unmarshalStructFields(target, data)
+			C.pn_data_exit(data)
+		} else {
+			setValue(target, pnType, getDescribed(data))
+		}
+	default:
+		panic(newUnmarshalError(pnType, target.Interface()))
+	}
+}
+
+// setValue assigns v, a Go value of the type Marshal would naturally produce
+// for pnType, into target, converting if target has a different but
+// convertible concrete type (e.g. decoding PN_INT into an int32 field).
+func setValue(target reflect.Value, pnType C.pn_type_t, v interface{}) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case target.Kind() == reflect.Interface:
+		target.Set(rv)
+	case rv.Type().ConvertibleTo(target.Type()):
+		target.Set(rv.Convert(target.Type()))
+	default:
+		panic(newUnmarshalError(pnType, target.Interface()))
+	}
+}
+
+func getList(data *C.pn_data_t) List {
+	count := int(C.pn_data_get_list(data))
+	list := make(List, 0, count)
+	C.pn_data_enter(data)
+	for C.pn_data_next(data) != 0 {
+		list = append(list, get(data))
+	}
+	C.pn_data_exit(data)
+	return list
+}
+
+func getMap(data *C.pn_data_t) Map {
+	count := int(C.pn_data_get_map(data)) / 2
+	m := make(Map, count)
+	C.pn_data_enter(data)
+	for C.pn_data_next(data) != 0 {
+		key := get(data)
+		C.pn_data_next(data)
+		m[key] = get(data)
+	}
+	C.pn_data_exit(data)
+	return m
+}
+
+// getArray decodes an AMQP array as an Array whose Values is a Go slice with
+// element type corresponding to the array's element type.
+func getArray(data *C.pn_data_t) Array {
+	count := int(C.pn_data_get_array(data))
+	elementType := AMQPType(C.pn_data_get_array_type(data))
+	sliceType := reflect.SliceOf(goElementType(elementType))
+	values := reflect.MakeSlice(sliceType, 0, count)
+	C.pn_data_enter(data)
+	for C.pn_data_next(data) != 0 {
+		elem := reflect.New(sliceType.Elem()).Elem()
+		set(elem, data)
+		values = reflect.Append(values, elem)
+	}
+	C.pn_data_exit(data)
+	return Array{ElementType: elementType, Values: values.Interface()}
+}
+
+// getDescribed decodes a described value. If its descriptor was registered
+// with RegisterDescribed, it allocates a new instance of the registered Go
+// type and decodes the body into it, returning a pointer to that instance.
+// Otherwise it returns a generic Described.
+func getDescribed(data *C.pn_data_t) interface{} {
+	C.pn_data_enter(data)
+	C.pn_data_next(data)
+	descriptor := get(data)
+	C.pn_data_next(data)
+	if t, ok := goTypeFor(descriptor); ok {
+		instance := reflect.New(t)
+		if t.Kind() == reflect.Struct {
+			unmarshalStructFields(instance.Elem(), data)
+		} else {
+			set(instance.Elem(), data)
+		}
+		C.pn_data_exit(data)
+		return instance.Interface()
+	}
+	value := get(data)
+	C.pn_data_exit(data)
+	return Described{Descriptor: descriptor, Value: value}
+}
+
+// goElementType returns the Go type used to represent a single element of an
+// AMQP array with the given element type, the reverse of amqpElementType.
+func goElementType(t AMQPType) reflect.Type {
+	switch t {
+	case Bool:
+		return reflect.TypeOf(bool(false))
+	case Ubyte:
+		return reflect.TypeOf(uint8(0))
+	case Byte:
+		return reflect.TypeOf(int8(0))
+	case Ushort:
+		return reflect.TypeOf(uint16(0))
+	case Short:
+		return reflect.TypeOf(int16(0))
+	case Uint:
+		return reflect.TypeOf(uint32(0))
+	case Int:
+		return reflect.TypeOf(int32(0))
+	case Char_:
+		return reflect.TypeOf(Char(0))
+	case Ulong:
+		return reflect.TypeOf(uint64(0))
+	case Long:
+		return reflect.TypeOf(int64(0))
+	case Timestamp:
+		return reflect.TypeOf(time.Time{})
+	case Float:
+		return reflect.TypeOf(float32(0))
+	case Double:
+		return reflect.TypeOf(float64(0))
+	case String_:
+		return reflect.TypeOf("")
+	case SymbolType:
+		return reflect.TypeOf(Symbol(""))
+	case Binary_:
+		return reflect.TypeOf(Binary(""))
+	case UuidType:
+		return reflect.TypeOf(UUID{})
+	case Decimal32Type:
+		return reflect.TypeOf(Decimal32(0))
+	case Decimal64Type:
+		return reflect.TypeOf(Decimal64(0))
+	case Decimal128Type:
+		return reflect.TypeOf(Decimal128{})
+	default:
+		return reflect.TypeOf((*interface{})(nil)).Elem()
+	}
+}